@@ -0,0 +1,769 @@
+// Package checkpoint provides a client for the HashiCorp checkpoint
+// service, which is used to perform automated "version checks" (similar to
+// update notifications) and anonymous usage reporting.
+//
+// This is the dotmesh-io fork, used to check for new versions of dotmesh
+// and to surface alerts from the checkpoint service to running daemons.
+package checkpoint
+
+import (
+	"bufio"
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultCheckInterval is how long a cached response is considered valid
+// when the caller does not specify a CacheDuration.
+const defaultCheckInterval = 48 * time.Hour
+
+// CheckParams are the parameters for Check.
+type CheckParams struct {
+	// Product and Version are used to lookup the correct product and
+	// version when checking for updates.
+	Product string
+	Version string
+
+	// Arch and OS are used to express the architecture and OS of the
+	// product. If not specified, runtime.GOARCH and runtime.GOOS are
+	// used.
+	Arch string
+	OS   string
+
+	// SignatureFile, if provided, is the path to a file containing a
+	// unique signature that identifies this user, generated the first
+	// time a check is made and reused after. The signature is used to
+	// help the checkpoint service estimate unique installations.
+	SignatureFile string
+
+	// CacheFile, if specified, points to a file to cache the results of
+	// the check. If this is empty, no caching is done.
+	//
+	// CacheDuration is the duration to cache the response for when the
+	// response carries no Cache-Control max-age. This defaults to 48
+	// hours if not set. A max-age on the response takes precedence, a
+	// "no-cache" response is cached but never considered fresh, and a
+	// "no-store" response is not written to CacheFile at all.
+	CacheFile     string
+	CacheDuration time.Duration
+
+	// Force, if true, will force the check even if CHECKPOINT_DISABLE
+	// is set.
+	Force bool
+
+	// Flags and ExtraFlags are added as query parameters on the
+	// check request. Flags are a static map of parameters, whereas
+	// ExtraFlags is a function so that flags that are expensive or
+	// change over time can be computed lazily and may also contain
+	// duplicate keys.
+	Flags      map[string]string
+	ExtraFlags func() []Flag
+
+	// URLs is an ordered list of alternate checkpoint endpoints to try
+	// if the primary endpoint (CHECKPOINT_URL, or the default
+	// checkpoint service) is unreachable. This is useful for
+	// air-gapped or geo-restricted environments that host their own
+	// internal checkpoint mirrors.
+	URLs []string
+
+	// RetryPolicy controls the exponential-backoff retry behavior used
+	// while iterating over the primary endpoint and URLs. If nil,
+	// DefaultRetryPolicy is used.
+	RetryPolicy *RetryPolicy
+
+	// HTTPClient is used to make the check request. If nil, a default
+	// client is used. Set this to inject a proxy-aware transport, a
+	// corporate TLS trust store, or similar. Per-attempt deadlines
+	// still come from RetryPolicy.Timeout via the request context, so
+	// this client does not need its own Timeout set.
+	HTTPClient *http.Client
+
+	// SignaturePublicKey, if set, requires every candidate endpoint to
+	// return a detached signature over its response (via the
+	// X-Checkpoint-Signature header, or the response's own Signature
+	// field) and verifies it against this key. A response with a
+	// missing or invalid signature is rejected with *ErrInvalidSignature.
+	// Use GenerateKey to create a matching key pair.
+	SignaturePublicKey ed25519.PublicKey
+
+	// Reporter, if set, is notified of check outcomes so callers can
+	// wire up metrics without checkpoint depending on any particular
+	// metrics library. If nil, outcomes are simply discarded. See the
+	// checkpoint/metrics subpackage for a Prometheus-backed Reporter.
+	Reporter Reporter
+}
+
+// Reporter receives check outcome events.
+type Reporter interface {
+	// CheckResult is called once per Check/CheckContext call with the
+	// outcome ("success", "error", "disabled", or "cache_hit") and how
+	// long the call took (zero for "disabled" and "cache_hit"). It is not
+	// called when the call fails before any request is made (an unreadable
+	// CacheFile or SignatureFile, or an unparsable endpoint URL). A
+	// "success" is still reported if writing CacheFile then fails.
+	CheckResult(result string, duration time.Duration)
+
+	// CacheHit is called whenever a fresh cached response is served
+	// without making a network request.
+	CacheHit()
+
+	// Outdated is called after every successful response, reporting
+	// whether the product is outdated for the reported current version.
+	// It is called on every success (not just when outdated is true) so
+	// a Reporter can clear any previously reported outdated state.
+	Outdated(product, version string, outdated bool)
+
+	// Alert is called once per alert present in a successful response.
+	Alert(level string)
+}
+
+// noopReporter is the default Reporter, used when CheckParams.Reporter is
+// nil, preserving checkpoint's zero-dependency behavior.
+type noopReporter struct{}
+
+func (noopReporter) CheckResult(string, time.Duration) {}
+func (noopReporter) CacheHit()                         {}
+func (noopReporter) Outdated(string, string, bool)     {}
+func (noopReporter) Alert(string)                      {}
+
+// RetryPolicy controls how Check retries across candidate endpoints (the
+// primary endpoint plus CheckParams.URLs) before giving up.
+type RetryPolicy struct {
+	// MaxAttempts is the number of passes to make over the full list of
+	// candidate endpoints before giving up. A value <= 0 falls back to
+	// DefaultRetryPolicy.MaxAttempts.
+	MaxAttempts int
+
+	// BaseDelay is the delay before the second pass; it doubles on each
+	// subsequent pass.
+	BaseDelay time.Duration
+
+	// Timeout bounds each individual HTTP request to a candidate
+	// endpoint.
+	Timeout time.Duration
+}
+
+// DefaultRetryPolicy is used when CheckParams.RetryPolicy is nil.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   250 * time.Millisecond,
+	Timeout:     10 * time.Second,
+}
+
+// Flag is a single extra query parameter for a check request. Unlike Flags
+// on CheckParams, a []Flag may contain duplicate keys.
+type Flag struct {
+	Key, Value string
+}
+
+// CheckResponse is the response for a check request.
+type CheckResponse struct {
+	Product             string
+	CurrentVersion      string        `json:"current_version"`
+	CurrentReleaseDate  int64         `json:"current_release_date"`
+	CurrentDownloadURL  string        `json:"current_download_url"`
+	CurrentChangelogURL string        `json:"current_changelog_url"`
+	ProjectWebsite      string        `json:"project_website"`
+	Outdated            bool          `json:"outdated"`
+	Alerts              []*CheckAlert `json:"alerts"`
+
+	// Signature is a base64-encoded detached ed25519 signature over the
+	// response's JSON object with the "signature" key removed, as
+	// re-encoded by encoding/json from a map (keys in sorted order). It is only
+	// consulted when CheckParams.SignaturePublicKey is set and the
+	// server did not supply an X-Checkpoint-Signature header instead.
+	Signature string `json:"signature,omitempty"`
+
+	// SourceURL is the candidate endpoint that served this response, so
+	// callers can log which mirror they ended up talking to. It is
+	// never part of the server's JSON payload, so it is empty on a
+	// response served from CacheFile or returned while checks are
+	// disabled.
+	SourceURL string `json:"-"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler. It otherwise decodes like an
+// ordinary CheckResponse, except that CurrentReleaseDate accepts either a
+// JSON number or a quoted string, and Alerts accepts either a JSON array
+// or a single alert object. This tolerates the inconsistent serialization
+// used by different checkpoint server implementations.
+func (r *CheckResponse) UnmarshalJSON(data []byte) error {
+	type alias CheckResponse
+	aux := &struct {
+		CurrentReleaseDate interface{} `json:"current_release_date"`
+		Alerts             interface{} `json:"alerts"`
+		*alias
+	}{
+		alias: (*alias)(r),
+	}
+
+	if err := json.Unmarshal(data, aux); err != nil {
+		return err
+	}
+
+	if aux.CurrentReleaseDate != nil {
+		d, err := parseReleaseDate(aux.CurrentReleaseDate)
+		if err != nil {
+			return err
+		}
+		r.CurrentReleaseDate = d
+	}
+
+	if aux.Alerts != nil {
+		alerts, err := parseAlerts(aux.Alerts)
+		if err != nil {
+			return err
+		}
+		r.Alerts = alerts
+	}
+
+	return nil
+}
+
+// ErrInvalidCheckResponse is returned when a checkpoint response field has
+// a JSON type that CheckResponse's lenient decoding doesn't know how to
+// coerce.
+type ErrInvalidCheckResponse struct {
+	Field string
+	Value interface{}
+}
+
+func (e *ErrInvalidCheckResponse) Error() string {
+	return fmt.Sprintf("checkpoint: field %q has unsupported type %T", e.Field, e.Value)
+}
+
+// parseReleaseDate coerces a decoded current_release_date value, which may
+// be a JSON number (the common case) or a quoted unix timestamp string.
+func parseReleaseDate(v interface{}) (int64, error) {
+	switch t := v.(type) {
+	case float64:
+		return int64(t), nil
+	case string:
+		return strconv.ParseInt(t, 10, 64)
+	default:
+		return 0, &ErrInvalidCheckResponse{Field: "current_release_date", Value: v}
+	}
+}
+
+// parseAlerts coerces a decoded alerts value, which may be a JSON array of
+// alerts (the common case) or a single alert object.
+func parseAlerts(v interface{}) ([]*CheckAlert, error) {
+	switch v.(type) {
+	case []interface{}:
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		var alerts []*CheckAlert
+		if err := json.Unmarshal(raw, &alerts); err != nil {
+			return nil, err
+		}
+		return alerts, nil
+	case map[string]interface{}:
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		var alert CheckAlert
+		if err := json.Unmarshal(raw, &alert); err != nil {
+			return nil, err
+		}
+		return []*CheckAlert{&alert}, nil
+	default:
+		return nil, &ErrInvalidCheckResponse{Field: "alerts", Value: v}
+	}
+}
+
+// CheckAlert is a single alert message from the checkpoint service.
+type CheckAlert struct {
+	ID      int    `json:"id"`
+	Date    int64  `json:"date"`
+	Message string `json:"message"`
+	URL     string `json:"url"`
+	Level   string `json:"level"`
+}
+
+// Check is a convenience wrapper around CheckContext using
+// context.Background().
+//
+// CHECKPOINT_DISABLE will disable this unless CheckParams.Force is set.
+// CHECKPOINT_URL can be used to override the URL of the checkpoint
+// service, which is primarily useful for testing.
+func Check(p *CheckParams) (*CheckResponse, error) {
+	return CheckContext(context.Background(), p)
+}
+
+// CheckContext checks for alerts and new version information against the
+// checkpoint service. The provided ctx bounds the overall call; it is
+// also the parent of each per-attempt timeout derived from
+// CheckParams.RetryPolicy, so canceling it (e.g. during shutdown) aborts
+// any in-flight request immediately.
+func CheckContext(ctx context.Context, p *CheckParams) (*CheckResponse, error) {
+	reporter := p.Reporter
+	if reporter == nil {
+		reporter = noopReporter{}
+	}
+
+	if disabled := os.Getenv("CHECKPOINT_DISABLE"); disabled != "" && !p.Force {
+		reporter.CheckResult("disabled", 0)
+		return &CheckResponse{}, nil
+	}
+
+	cacheDuration := p.CacheDuration
+	if cacheDuration == 0 {
+		cacheDuration = defaultCheckInterval
+	}
+
+	if p.CacheFile != "" {
+		r, fresh, err := checkCache(p.CacheFile)
+		if err != nil {
+			return nil, err
+		}
+		if fresh {
+			reporter.CacheHit()
+			reporter.CheckResult("cache_hit", 0)
+			return r, nil
+		}
+	}
+
+	urls, err := candidateURLs(p)
+	if err != nil {
+		return nil, err
+	}
+
+	query, err := buildQuery(p)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := DefaultRetryPolicy
+	if p.RetryPolicy != nil {
+		policy = *p.RetryPolicy
+	}
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = DefaultRetryPolicy.MaxAttempts
+	}
+
+	client := p.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	var result *CheckResponse
+	var sourceURL string
+	var cc cacheControl
+	var lastErr error
+
+	start := time.Now()
+
+attempts:
+	for attempt := 0; attempt < policy.MaxAttempts; attempt++ {
+		if attempt > 0 {
+			timer := time.NewTimer(policy.BaseDelay << uint(attempt-1))
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				lastErr = ctx.Err()
+				break attempts
+			}
+		}
+
+		if ctx.Err() != nil {
+			lastErr = ctx.Err()
+			break attempts
+		}
+
+		for _, u := range urls {
+			base := u.String()
+
+			full := *u
+			full.RawQuery = query.Encode()
+
+			r, directives, err := fetchCheckResponse(ctx, client, full.String(), policy.Timeout, p.SignaturePublicKey)
+			if err != nil {
+				lastErr = err
+				continue
+			}
+
+			result = r
+			sourceURL = base
+			cc = directives
+			break attempts
+		}
+	}
+
+	duration := time.Since(start)
+
+	if result == nil {
+		reporter.CheckResult("error", duration)
+		return nil, fmt.Errorf("checkpoint: all endpoints failed: %w", lastErr)
+	}
+	result.SourceURL = sourceURL
+
+	reporter.CheckResult("success", duration)
+	reporter.Outdated(p.Product, result.CurrentVersion, result.Outdated)
+	for _, alert := range result.Alerts {
+		if alert != nil {
+			reporter.Alert(alert.Level)
+		}
+	}
+
+	if p.CacheFile != "" && !cc.NoStore {
+		maxAge := cacheDuration
+		switch {
+		case cc.NoCache:
+			maxAge = 0
+		case cc.HasMaxAge:
+			maxAge = cc.MaxAge
+		}
+		if err := writeCache(p.CacheFile, result, maxAge); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// candidateURLs returns the ordered list of checkpoint endpoints to try:
+// the primary endpoint (CHECKPOINT_URL, or the default checkpoint
+// service, if unset) followed by p.URLs in order.
+func candidateURLs(p *CheckParams) ([]*url.URL, error) {
+	primary := &url.URL{
+		Scheme: "https",
+		Host:   "checkpoint-api.hashicorp.com",
+		Path:   fmt.Sprintf("/v1/check/%s", p.Product),
+	}
+	if checkpointURL := os.Getenv("CHECKPOINT_URL"); checkpointURL != "" {
+		parsed, err := url.Parse(checkpointURL)
+		if err != nil {
+			return nil, err
+		}
+		primary.Scheme = parsed.Scheme
+		primary.Host = parsed.Host
+		primary.Path = parsed.Path
+	}
+
+	urls := make([]*url.URL, 0, len(p.URLs)+1)
+	urls = append(urls, primary)
+	for _, raw := range p.URLs {
+		parsed, err := url.Parse(raw)
+		if err != nil {
+			return nil, err
+		}
+		urls = append(urls, parsed)
+	}
+
+	return urls, nil
+}
+
+// buildQuery builds the query parameters shared by every candidate
+// endpoint: version/arch/os, the install signature (if configured), and
+// any caller-supplied flags.
+func buildQuery(p *CheckParams) (url.Values, error) {
+	v := url.Values{}
+	v.Set("version", p.Version)
+	v.Set("arch", valueOrDefault(p.Arch, runtime.GOARCH))
+	v.Set("os", valueOrDefault(p.OS, runtime.GOOS))
+
+	if p.SignatureFile != "" {
+		signature, err := checkSignature(p.SignatureFile)
+		if err != nil {
+			return nil, err
+		}
+		v.Set("signature", signature)
+	}
+
+	for k, val := range p.Flags {
+		v.Set(k, val)
+	}
+	if p.ExtraFlags != nil {
+		for _, f := range p.ExtraFlags() {
+			v.Add(f.Key, f.Value)
+		}
+	}
+
+	return v, nil
+}
+
+// cacheControl captures the Cache-Control directives on a checkpoint
+// response that are relevant to CacheFile handling.
+type cacheControl struct {
+	// HasMaxAge reports whether the response carried a usable max-age
+	// directive; MaxAge is only meaningful when this is true.
+	HasMaxAge bool
+	MaxAge    time.Duration
+
+	// NoStore means the response must not be written to CacheFile at
+	// all.
+	NoStore bool
+
+	// NoCache means the response may be written to CacheFile, but every
+	// subsequent call must revalidate (i.e. it is never considered
+	// fresh).
+	NoCache bool
+}
+
+// parseCacheControl parses the handful of Cache-Control directives Check
+// cares about. Unknown directives are ignored.
+func parseCacheControl(header string) cacheControl {
+	var cc cacheControl
+	for _, part := range strings.Split(header, ",") {
+		part = strings.ToLower(strings.TrimSpace(part))
+		switch {
+		case part == "no-store":
+			cc.NoStore = true
+		case part == "no-cache":
+			cc.NoCache = true
+		case strings.HasPrefix(part, "max-age="):
+			secs, err := strconv.ParseInt(strings.TrimPrefix(part, "max-age="), 10, 64)
+			if err == nil && secs >= 0 {
+				cc.MaxAge = time.Duration(secs) * time.Second
+				cc.HasMaxAge = true
+			}
+		}
+	}
+	return cc
+}
+
+// fetchCheckResponse performs a single bounded HTTP GET against rawURL and
+// decodes a well-formed CheckResponse from a 2xx reply, along with the
+// reply's Cache-Control directives. The request is bound to ctx, further
+// scoped to timeout if positive. If pub is non-nil, the response must
+// carry a valid detached signature or fetchCheckResponse returns
+// *ErrInvalidSignature.
+func fetchCheckResponse(ctx context.Context, client *http.Client, rawURL string, timeout time.Duration, pub ed25519.PublicKey) (*CheckResponse, cacheControl, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, cacheControl{}, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, cacheControl{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, cacheControl{}, fmt.Errorf("checkpoint: unexpected status %d from %s", resp.StatusCode, rawURL)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, cacheControl{}, err
+	}
+
+	var result CheckResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, cacheControl{}, err
+	}
+
+	if pub != nil {
+		if err := verifySignature(pub, body, resp.Header.Get("X-Checkpoint-Signature"), &result); err != nil {
+			return nil, cacheControl{}, err
+		}
+	}
+
+	return &result, parseCacheControl(resp.Header.Get("Cache-Control")), nil
+}
+
+// checkSignature reads the signature from path, generating and persisting
+// a new random one if the file does not yet exist.
+func checkSignature(path string) (string, error) {
+	_, err := os.Stat(path)
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+
+	if err == nil {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		if sig := strings.TrimSpace(string(data)); sig != "" {
+			return sig, nil
+		}
+	}
+
+	signature := fmt.Sprintf("%d", rand.New(rand.NewSource(time.Now().UnixNano())).Int63())
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, []byte(signature), 0644); err != nil {
+		return "", err
+	}
+
+	return signature, nil
+}
+
+// checkCache reads a cached CheckResponse from path. fresh reports whether
+// the cached entry is still within the max-age it was written with (a
+// max-age of zero, as written for a "no-cache" response, is never fresh).
+// If the cache file does not exist, both return values are zero and err
+// is nil.
+func checkCache(path string) (r *CheckResponse, fresh bool, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	defer f.Close()
+
+	br := bufio.NewReader(f)
+
+	// A cache file written before the max-age header line was introduced
+	// has only a timestamp line followed directly by the JSON body. Treat
+	// any failure to parse the (possibly old-format) file as a cache miss
+	// rather than a hard error, so upgrading doesn't break existing
+	// CacheFile users until they happen to delete the file by hand.
+	tsLine, err := br.ReadString('\n')
+	if err != nil {
+		return nil, false, nil
+	}
+	ts, err := strconv.ParseInt(strings.TrimSpace(tsLine), 10, 64)
+	if err != nil {
+		return nil, false, nil
+	}
+
+	maxAgeLine, err := br.ReadString('\n')
+	if err != nil {
+		return nil, false, nil
+	}
+	maxAgeSecs, err := strconv.ParseInt(strings.TrimSpace(maxAgeLine), 10, 64)
+	if err != nil {
+		return nil, false, nil
+	}
+
+	var result CheckResponse
+	if err := json.NewDecoder(br).Decode(&result); err != nil {
+		return nil, false, nil
+	}
+
+	fresh = maxAgeSecs > 0 && time.Since(time.Unix(ts, 0)) < time.Duration(maxAgeSecs)*time.Second
+	return &result, fresh, nil
+}
+
+// writeCache persists r to path along with the fetchedAt timestamp and
+// maxAge it should be considered valid for, creating any intermediate
+// directories that don't yet exist. A maxAge of zero marks the entry as
+// always-revalidate (used for a "no-cache" response).
+func writeCache(path string, r *CheckResponse, maxAge time.Duration) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := fmt.Fprintf(f, "%d\n%d\n", time.Now().Unix(), int64(maxAge/time.Second)); err != nil {
+		return err
+	}
+	return json.NewEncoder(f).Encode(r)
+}
+
+// CheckTicker is returned by CheckInterval and can be used to stop the
+// automatic checking of the checkpoint service.
+type CheckTicker struct {
+	stopCh   chan struct{}
+	cancel   context.CancelFunc
+	stopped  bool
+	stopLock sync.Mutex
+}
+
+// Stop stops the CheckInterval goroutine from checking further, canceling
+// any in-flight request. It is safe to call Stop more than once.
+func (c *CheckTicker) Stop() {
+	c.stopLock.Lock()
+	defer c.stopLock.Unlock()
+
+	if !c.stopped {
+		c.stopped = true
+		close(c.stopCh)
+		if c.cancel != nil {
+			c.cancel()
+		}
+	}
+}
+
+// CheckInterval is a convenience wrapper around CheckIntervalContext using
+// context.Background().
+func CheckInterval(p *CheckParams, interval time.Duration, cb func(*CheckResponse, error)) *CheckTicker {
+	return CheckIntervalContext(context.Background(), p, interval, cb)
+}
+
+// CheckIntervalContext checks on the given interval, invoking the
+// callback each time. Unless CHECKPOINT_DISABLE is set, the first check
+// happens immediately so callers find out about alerts as soon as
+// possible; subsequent checks are staggered around the given interval.
+//
+// Call Stop on the returned CheckTicker to stop checking; this also
+// cancels ctx, aborting any request that is still in flight.
+func CheckIntervalContext(ctx context.Context, p *CheckParams, interval time.Duration, cb func(*CheckResponse, error)) *CheckTicker {
+	ticker := &CheckTicker{stopCh: make(chan struct{})}
+
+	if disabled := os.Getenv("CHECKPOINT_DISABLE"); disabled != "" && !p.Force {
+		return ticker
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	ticker.cancel = cancel
+
+	go func() {
+		defer cancel()
+
+		check := func() {
+			resp, err := CheckContext(ctx, p)
+			cb(resp, err)
+		}
+
+		check()
+		for {
+			select {
+			case <-time.After(randomStagger(interval)):
+				check()
+			case <-ticker.stopCh:
+				return
+			}
+		}
+	}()
+
+	return ticker
+}
+
+// randomStagger returns an interval that is between 75% and 125% of the
+// given interval, so that many processes started at the same time don't
+// all hit the checkpoint service simultaneously.
+func randomStagger(intv time.Duration) time.Duration {
+	stagger := time.Duration(rand.Int63n(int64(intv / 2)))
+	return (intv - intv/4) + stagger
+}
+
+func valueOrDefault(v, def string) string {
+	if v != "" {
+		return v
+	}
+	return def
+}
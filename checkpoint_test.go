@@ -1,7 +1,11 @@
 package checkpoint
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
@@ -52,6 +56,7 @@ func TestCheck(t *testing.T) {
 		ProjectWebsite:      "https://test-app.used-for-testing",
 		Outdated:            false,
 		Alerts:              nil,
+		SourceURL:           srv.URL,
 	}
 
 	actual, err := Check(&CheckParams{
@@ -77,6 +82,7 @@ func TestCheck_flags(t *testing.T) {
 		ProjectWebsite:      "https://test-app.used-for-testing",
 		Outdated:            false,
 		Alerts:              nil,
+		SourceURL:           srv.URL,
 	}
 
 	actual, err := Check(&CheckParams{
@@ -191,6 +197,373 @@ func TestCheck_cacheNested(t *testing.T) {
 	}
 }
 
+func TestCheck_cacheOldFormat(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkpoint")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	cacheFile := filepath.Join(dir, "cache")
+
+	// Pre-v2 cache files had no max-age line: just a timestamp followed
+	// directly by the JSON body.
+	old := fmt.Sprintf("%d\n{\"Product\":\"test-app\",\"current_version\":\"0.5.0\"}", time.Now().Unix())
+	if err := ioutil.WriteFile(cacheFile, []byte(old), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	actual, err := Check(&CheckParams{
+		Product:   "test-app",
+		Version:   "1.0.0",
+		CacheFile: cacheFile,
+	})
+	if err != nil {
+		t.Fatalf("an old-format cache file should be treated as a miss, not an error: %s", err)
+	}
+	if actual.CurrentVersion != "1.0.0" {
+		t.Fatalf("expected a live result, got stale cache contents: %#v", actual)
+	}
+}
+
+func TestCheck_cacheControlMaxAge(t *testing.T) {
+	var hits int
+	cacheSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.Header().Set("Cache-Control", "max-age=1")
+		json.NewEncoder(w).Encode(&CheckResponse{CurrentVersion: "1.0.0"})
+	}))
+	defer cacheSrv.Close()
+
+	os.Setenv("CHECKPOINT_URL", cacheSrv.URL)
+	defer os.Setenv("CHECKPOINT_URL", srv.URL)
+
+	dir, err := ioutil.TempDir("", "checkpoint")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	cacheFile := filepath.Join(dir, "cache")
+	params := &CheckParams{Product: "test-app", Version: "1.0.0", CacheFile: cacheFile}
+
+	if _, err := Check(params); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := Check(params); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if hits != 1 {
+		t.Fatalf("expected 1 hit while max-age is fresh, got %d", hits)
+	}
+
+	time.Sleep(1100 * time.Millisecond)
+
+	if _, err := Check(params); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if hits != 2 {
+		t.Fatalf("expected re-fetch after max-age expired, got %d hits", hits)
+	}
+}
+
+func TestCheck_cacheControlNoStore(t *testing.T) {
+	cacheSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-store")
+		json.NewEncoder(w).Encode(&CheckResponse{CurrentVersion: "1.0.0"})
+	}))
+	defer cacheSrv.Close()
+
+	os.Setenv("CHECKPOINT_URL", cacheSrv.URL)
+	defer os.Setenv("CHECKPOINT_URL", srv.URL)
+
+	dir, err := ioutil.TempDir("", "checkpoint")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	cacheFile := filepath.Join(dir, "cache")
+
+	if _, err := Check(&CheckParams{Product: "test-app", Version: "1.0.0", CacheFile: cacheFile}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if _, err := os.Stat(cacheFile); !os.IsNotExist(err) {
+		t.Fatalf("expected no cache file to be written, stat err: %v", err)
+	}
+}
+
+func TestCheckContext_canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := CheckContext(ctx, &CheckParams{
+		Product: "test-app",
+		Version: "1.0.0",
+		RetryPolicy: &RetryPolicy{
+			MaxAttempts: 1,
+			Timeout:     time.Second,
+		},
+	})
+
+	if err == nil {
+		t.Fatal("expected an error from an already-canceled context")
+	}
+}
+
+func TestCheckContext_canceledDuringBackoff(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	start := time.Now()
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+
+	downSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer downSrv.Close()
+
+	os.Setenv("CHECKPOINT_URL", downSrv.URL)
+	defer os.Setenv("CHECKPOINT_URL", srv.URL)
+
+	_, err := CheckContext(ctx, &CheckParams{
+		Product: "test-app",
+		Version: "1.0.0",
+		RetryPolicy: &RetryPolicy{
+			MaxAttempts: 5,
+			BaseDelay:   time.Second,
+			Timeout:     time.Second,
+		},
+	})
+
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("canceling the context should abort the backoff immediately, took %s", elapsed)
+	}
+	if err == nil {
+		t.Fatal("expected an error from a canceled context")
+	}
+}
+
+func TestCheck_httpClient(t *testing.T) {
+	var used bool
+	client := &http.Client{
+		Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			used = true
+			return http.DefaultTransport.RoundTrip(r)
+		}),
+	}
+
+	_, err := Check(&CheckParams{
+		Product:    "test-app",
+		Version:    "1.0.0",
+		HTTPClient: client,
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if !used {
+		t.Fatal("expected the custom HTTPClient to be used")
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestCheck_lenientReleaseDateString(t *testing.T) {
+	lenientSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"current_version":"1.0.0","current_release_date":"1460459932"}`)
+	}))
+	defer lenientSrv.Close()
+
+	os.Setenv("CHECKPOINT_URL", lenientSrv.URL)
+	defer os.Setenv("CHECKPOINT_URL", srv.URL)
+
+	actual, err := Check(&CheckParams{Product: "test-app", Version: "1.0.0"})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if actual.CurrentReleaseDate != 1460459932 {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func TestCheck_lenientAlertsSingleObject(t *testing.T) {
+	lenientSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"current_version":"1.0.0","alerts":{"id":1,"message":"hello","level":"info"}}`)
+	}))
+	defer lenientSrv.Close()
+
+	os.Setenv("CHECKPOINT_URL", lenientSrv.URL)
+	defer os.Setenv("CHECKPOINT_URL", srv.URL)
+
+	actual, err := Check(&CheckParams{Product: "test-app", Version: "1.0.0"})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(actual.Alerts) != 1 || actual.Alerts[0].Message != "hello" {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func TestCheck_lenientReleaseDateInvalid(t *testing.T) {
+	lenientSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"current_version":"1.0.0","current_release_date":true}`)
+	}))
+	defer lenientSrv.Close()
+
+	os.Setenv("CHECKPOINT_URL", lenientSrv.URL)
+	defer os.Setenv("CHECKPOINT_URL", srv.URL)
+
+	_, err := Check(&CheckParams{
+		Product:     "test-app",
+		Version:     "1.0.0",
+		RetryPolicy: &RetryPolicy{MaxAttempts: 1, Timeout: time.Second},
+	})
+
+	var invalid *ErrInvalidCheckResponse
+	if !errors.As(err, &invalid) {
+		t.Fatalf("expected *ErrInvalidCheckResponse, got: %v", err)
+	}
+}
+
+type recordingReporter struct {
+	results       []string
+	cacheHit      int
+	outdated      []string
+	outdatedCalls int
+	alerts        []string
+}
+
+func (r *recordingReporter) CheckResult(result string, _ time.Duration) {
+	r.results = append(r.results, result)
+}
+func (r *recordingReporter) CacheHit() { r.cacheHit++ }
+func (r *recordingReporter) Outdated(product, version string, outdated bool) {
+	r.outdatedCalls++
+	if outdated {
+		r.outdated = append(r.outdated, product+"@"+version)
+	}
+}
+func (r *recordingReporter) Alert(level string) {
+	r.alerts = append(r.alerts, level)
+}
+
+func TestCheck_reporter(t *testing.T) {
+	reporting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(&CheckResponse{
+			CurrentVersion: "1.0.0",
+			Outdated:       true,
+			Alerts: []*CheckAlert{
+				{Message: "upgrade soon", Level: "warning"},
+			},
+		})
+	}))
+	defer reporting.Close()
+
+	os.Setenv("CHECKPOINT_URL", reporting.URL)
+	defer os.Setenv("CHECKPOINT_URL", srv.URL)
+
+	reporter := &recordingReporter{}
+	if _, err := Check(&CheckParams{Product: "test-app", Version: "0.9.0", Reporter: reporter}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if !reflect.DeepEqual(reporter.results, []string{"success"}) {
+		t.Fatalf("bad results: %#v", reporter.results)
+	}
+	if !reflect.DeepEqual(reporter.outdated, []string{"test-app@1.0.0"}) {
+		t.Fatalf("bad outdated: %#v", reporter.outdated)
+	}
+	if !reflect.DeepEqual(reporter.alerts, []string{"warning"}) {
+		t.Fatalf("bad alerts: %#v", reporter.alerts)
+	}
+}
+
+func TestCheck_reporterOutdatedClearsOnSuccess(t *testing.T) {
+	reporter := &recordingReporter{}
+	if _, err := Check(&CheckParams{Product: "test-app", Version: "1.0.0", Reporter: reporter}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if reporter.outdatedCalls != 1 {
+		t.Fatalf("expected Outdated to be called once per successful check, got %d", reporter.outdatedCalls)
+	}
+	if len(reporter.outdated) != 0 {
+		t.Fatalf("expected no outdated product recorded, got %#v", reporter.outdated)
+	}
+}
+
+func TestCheck_disabledReporter(t *testing.T) {
+	os.Setenv("CHECKPOINT_DISABLE", "1")
+	defer os.Setenv("CHECKPOINT_DISABLE", "")
+
+	reporter := &recordingReporter{}
+	if _, err := Check(&CheckParams{Product: "test-app", Version: "1.0.0", Reporter: reporter}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if !reflect.DeepEqual(reporter.results, []string{"disabled"}) {
+		t.Fatalf("bad results: %#v", reporter.results)
+	}
+}
+
+func TestCheck_urlsFallback(t *testing.T) {
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+
+	expected := &CheckResponse{
+		CurrentVersion:      "1.0.0",
+		CurrentReleaseDate:  1460459932, // 2016-04-12 11:18:52
+		CurrentDownloadURL:  "https://test-app.used-for-testing",
+		CurrentChangelogURL: "https://test-app.used-for-testing",
+		ProjectWebsite:      "https://test-app.used-for-testing",
+		Outdated:            false,
+		Alerts:              nil,
+		SourceURL:           srv.URL,
+	}
+
+	os.Setenv("CHECKPOINT_URL", down.URL)
+	defer os.Setenv("CHECKPOINT_URL", srv.URL)
+
+	actual, err := Check(&CheckParams{
+		Product: "test-app",
+		Version: "1.0.0",
+		URLs:    []string{srv.URL},
+		RetryPolicy: &RetryPolicy{
+			MaxAttempts: 1,
+			Timeout:     time.Second,
+		},
+	})
+
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func TestCheck_retryPolicyZeroMaxAttempts(t *testing.T) {
+	actual, err := Check(&CheckParams{
+		Product: "test-app",
+		Version: "1.0.0",
+		RetryPolicy: &RetryPolicy{
+			Timeout: time.Second,
+		},
+	})
+	if err != nil {
+		t.Fatalf("a zero-value MaxAttempts should fall back to the default, got err: %s", err)
+	}
+	if actual.CurrentVersion != "1.0.0" {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
 func TestCheckInterval(t *testing.T) {
 	expected := &CheckResponse{
 		CurrentVersion:      "1.0.0",
@@ -200,6 +573,7 @@ func TestCheckInterval(t *testing.T) {
 		ProjectWebsite:      "https://test-app.used-for-testing",
 		Outdated:            false,
 		Alerts:              nil,
+		SourceURL:           srv.URL,
 	}
 
 	params := &CheckParams{
@@ -262,6 +636,7 @@ func TestCheckInterval_immediate(t *testing.T) {
 		ProjectWebsite:      "https://test-app.used-for-testing",
 		Outdated:            false,
 		Alerts:              nil,
+		SourceURL:           srv.URL,
 	}
 
 	params := &CheckParams{
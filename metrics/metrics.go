@@ -0,0 +1,87 @@
+// Package metrics provides a Prometheus-backed checkpoint.Reporter.
+//
+// Importing this package is optional: the root checkpoint package has no
+// required dependency on Prometheus. Callers who want metrics construct a
+// Reporter here and set it on checkpoint.CheckParams.Reporter; callers who
+// don't can ignore this package entirely, or plug in their own
+// checkpoint.Reporter (OpenTelemetry, statsd, ...).
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	requestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "checkpoint_requests_total",
+		Help: "Total number of checkpoint check attempts, by outcome.",
+	}, []string{"result"})
+
+	requestDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name: "checkpoint_request_duration_seconds",
+		Help: "Duration of checkpoint check calls that reached the network, in seconds.",
+	})
+
+	cacheHitsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "checkpoint_cache_hits_total",
+		Help: "Total number of checkpoint checks served from a fresh CacheFile.",
+	})
+
+	// Labeled by product only (not version): the gauge reflects whether
+	// the *last* successful check found the product outdated, so it must
+	// be reset on every non-outdated success rather than accumulating a
+	// new label per version ever seen.
+	outdatedGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "checkpoint_outdated",
+		Help: "Set to 1 when the last successful check reported the product as outdated, 0 otherwise.",
+	}, []string{"product"})
+
+	alertsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "checkpoint_alerts_total",
+		Help: "Total number of alerts seen in checkpoint responses, by level.",
+	}, []string{"level"})
+)
+
+func init() {
+	prometheus.MustRegister(requestsTotal, requestDuration, cacheHitsTotal, outdatedGauge, alertsTotal)
+}
+
+// Reporter is a checkpoint.Reporter backed by prometheus.DefaultRegisterer.
+type Reporter struct{}
+
+// New returns a Reporter that records outcomes to
+// prometheus.DefaultRegisterer.
+func New() *Reporter {
+	return &Reporter{}
+}
+
+// CheckResult implements checkpoint.Reporter.
+func (*Reporter) CheckResult(result string, duration time.Duration) {
+	requestsTotal.WithLabelValues(result).Inc()
+	if duration > 0 {
+		requestDuration.Observe(duration.Seconds())
+	}
+}
+
+// CacheHit implements checkpoint.Reporter.
+func (*Reporter) CacheHit() {
+	cacheHitsTotal.Inc()
+}
+
+// Outdated implements checkpoint.Reporter. It is called on every
+// successful check, so it resets outdatedGauge back to 0 once the
+// product is current again.
+func (*Reporter) Outdated(product, version string, outdated bool) {
+	v := 0.0
+	if outdated {
+		v = 1
+	}
+	outdatedGauge.WithLabelValues(product).Set(v)
+}
+
+// Alert implements checkpoint.Reporter.
+func (*Reporter) Alert(level string) {
+	alertsTotal.WithLabelValues(level).Inc()
+}
@@ -0,0 +1,77 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestReporter_CheckResult(t *testing.T) {
+	r := New()
+
+	durationSamples := func() uint64 {
+		mfs, err := prometheus.DefaultGatherer.Gather()
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		for _, mf := range mfs {
+			if mf.GetName() == "checkpoint_request_duration_seconds" {
+				return mf.GetMetric()[0].GetHistogram().GetSampleCount()
+			}
+		}
+		return 0
+	}
+
+	before := testutil.ToFloat64(requestsTotal.WithLabelValues("success"))
+	samplesBefore := durationSamples()
+	r.CheckResult("success", 250*time.Millisecond)
+	after := testutil.ToFloat64(requestsTotal.WithLabelValues("success"))
+
+	if after != before+1 {
+		t.Fatalf("expected requestsTotal{result=success} to increment by 1, got %v -> %v", before, after)
+	}
+
+	if samplesAfter := durationSamples(); samplesAfter != samplesBefore+1 {
+		t.Fatalf("expected CheckResult to observe a duration sample, got %d -> %d samples", samplesBefore, samplesAfter)
+	}
+}
+
+func TestReporter_CacheHit(t *testing.T) {
+	r := New()
+
+	before := testutil.ToFloat64(cacheHitsTotal)
+	r.CacheHit()
+	after := testutil.ToFloat64(cacheHitsTotal)
+
+	if after != before+1 {
+		t.Fatalf("expected cacheHitsTotal to increment by 1, got %v -> %v", before, after)
+	}
+}
+
+func TestReporter_Outdated(t *testing.T) {
+	r := New()
+
+	r.Outdated("test-app", "1.0.0", true)
+	if got := testutil.ToFloat64(outdatedGauge.WithLabelValues("test-app")); got != 1 {
+		t.Fatalf("expected outdatedGauge{product=test-app} to be 1, got %v", got)
+	}
+
+	r.Outdated("test-app", "1.1.0", false)
+	if got := testutil.ToFloat64(outdatedGauge.WithLabelValues("test-app")); got != 0 {
+		t.Fatalf("expected outdatedGauge{product=test-app} to reset to 0 on the next non-outdated check, got %v", got)
+	}
+}
+
+func TestReporter_Alert(t *testing.T) {
+	r := New()
+
+	before := testutil.ToFloat64(alertsTotal.WithLabelValues("warning"))
+	r.Alert("warning")
+	after := testutil.ToFloat64(alertsTotal.WithLabelValues("warning"))
+
+	if after != before+1 {
+		t.Fatalf("expected alertsTotal{level=warning} to increment by 1, got %v -> %v", before, after)
+	}
+}
@@ -0,0 +1,73 @@
+package checkpoint
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+)
+
+// GenerateKey generates a new ed25519 key pair suitable for signing
+// checkpoint responses on the server side (the private key) and
+// verifying them on the client side via CheckParams.SignaturePublicKey
+// (the public key).
+func GenerateKey() (ed25519.PublicKey, ed25519.PrivateKey, error) {
+	return ed25519.GenerateKey(rand.Reader)
+}
+
+// ErrInvalidSignature is returned by Check when CheckParams.SignaturePublicKey
+// is set and the response's signature is missing or does not verify.
+type ErrInvalidSignature struct {
+	Reason string
+}
+
+func (e *ErrInvalidSignature) Error() string {
+	return fmt.Sprintf("checkpoint: invalid response signature: %s", e.Reason)
+}
+
+// verifySignature checks a response's detached signature, preferring the
+// X-Checkpoint-Signature header (signed over the raw response body) and
+// falling back to the response's own "signature" field (signed over the
+// response with that field removed).
+//
+// The embedded-field path canonicalizes by decoding the raw wire bytes
+// into a map and deleting the "signature" key, rather than re-marshaling
+// the decoded Go struct: re-marshaling the struct only reproduces the
+// signed bytes when the signer happens to match encoding/json's exact
+// field order and zero-value behavior, which isn't something an
+// independent (e.g. non-Go) signer can be expected to do.
+func verifySignature(pub ed25519.PublicKey, rawBody []byte, headerSig string, result *CheckResponse) error {
+	sigB64 := headerSig
+	canonical := rawBody
+
+	if sigB64 == "" {
+		if result.Signature == "" {
+			return &ErrInvalidSignature{Reason: "response carried no signature"}
+		}
+		sigB64 = result.Signature
+
+		var fields map[string]interface{}
+		if err := json.Unmarshal(rawBody, &fields); err != nil {
+			return err
+		}
+		delete(fields, "signature")
+
+		b, err := json.Marshal(fields)
+		if err != nil {
+			return err
+		}
+		canonical = b
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(sigB64)
+	if err != nil {
+		return &ErrInvalidSignature{Reason: "signature is not valid base64"}
+	}
+
+	if !ed25519.Verify(pub, canonical, sig) {
+		return &ErrInvalidSignature{Reason: "signature does not match response"}
+	}
+
+	return nil
+}
@@ -0,0 +1,188 @@
+package checkpoint
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestCheck_signatureHeader(t *testing.T) {
+	pub, priv, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	sigSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response := &CheckResponse{CurrentVersion: "1.0.0"}
+		body, err := json.Marshal(response)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		sig := ed25519.Sign(priv, body)
+		w.Header().Set("X-Checkpoint-Signature", base64.StdEncoding.EncodeToString(sig))
+		w.Write(body)
+	}))
+	defer sigSrv.Close()
+
+	os.Setenv("CHECKPOINT_URL", sigSrv.URL)
+	defer os.Setenv("CHECKPOINT_URL", srv.URL)
+
+	actual, err := Check(&CheckParams{
+		Product:            "test-app",
+		Version:            "1.0.0",
+		SignaturePublicKey: pub,
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if actual.CurrentVersion != "1.0.0" {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func TestCheck_signatureEmbedded(t *testing.T) {
+	pub, priv, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	sigSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response := &CheckResponse{CurrentVersion: "1.0.0"}
+		structBytes, err := json.Marshal(response)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		// Sign the same map-canonicalized form verifySignature will
+		// reconstruct from the wire bytes, not the raw struct encoding.
+		var fields map[string]interface{}
+		if err := json.Unmarshal(structBytes, &fields); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		canonical, err := json.Marshal(fields)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		response.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(priv, canonical))
+
+		json.NewEncoder(w).Encode(response)
+	}))
+	defer sigSrv.Close()
+
+	os.Setenv("CHECKPOINT_URL", sigSrv.URL)
+	defer os.Setenv("CHECKPOINT_URL", srv.URL)
+
+	actual, err := Check(&CheckParams{
+		Product:            "test-app",
+		Version:            "1.0.0",
+		SignaturePublicKey: pub,
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if actual.CurrentVersion != "1.0.0" {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+// TestCheck_signatureEmbeddedIndependentSigner verifies the embedded-field
+// path against a response that was never produced via encoding/json on a
+// CheckResponse at all, the way an independently implemented (e.g.
+// non-Go) signer would build one: hand-rolled field order, no zero-value
+// filler fields. This is the "malicious mirror" threat model the
+// signature feature exists for, so verification must not depend on the
+// signer reproducing Go's struct serialization.
+func TestCheck_signatureEmbeddedIndependentSigner(t *testing.T) {
+	pub, priv, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	sigSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw := `{"outdated":false,"current_version":"1.0.0","project_website":"https://example.com"}`
+
+		var fields map[string]interface{}
+		if err := json.Unmarshal([]byte(raw), &fields); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		canonical, err := json.Marshal(fields)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		fields["signature"] = base64.StdEncoding.EncodeToString(ed25519.Sign(priv, canonical))
+
+		wire, err := json.Marshal(fields)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		w.Write(wire)
+	}))
+	defer sigSrv.Close()
+
+	os.Setenv("CHECKPOINT_URL", sigSrv.URL)
+	defer os.Setenv("CHECKPOINT_URL", srv.URL)
+
+	actual, err := Check(&CheckParams{
+		Product:            "test-app",
+		Version:            "1.0.0",
+		SignaturePublicKey: pub,
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if actual.CurrentVersion != "1.0.0" || actual.ProjectWebsite != "https://example.com" {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func TestCheck_signatureMissing(t *testing.T) {
+	pub, _, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	_, err = Check(&CheckParams{
+		Product:            "test-app",
+		Version:            "1.0.0",
+		SignaturePublicKey: pub,
+		RetryPolicy:        &RetryPolicy{MaxAttempts: 1, Timeout: time.Second},
+	})
+
+	var sigErr *ErrInvalidSignature
+	if !errors.As(err, &sigErr) {
+		t.Fatalf("expected *ErrInvalidSignature, got: %v", err)
+	}
+}
+
+func TestCheck_signatureInvalid(t *testing.T) {
+	pub, _, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	badSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Checkpoint-Signature", base64.StdEncoding.EncodeToString([]byte("not-a-real-signature")))
+		json.NewEncoder(w).Encode(&CheckResponse{CurrentVersion: "1.0.0"})
+	}))
+	defer badSrv.Close()
+
+	os.Setenv("CHECKPOINT_URL", badSrv.URL)
+	defer os.Setenv("CHECKPOINT_URL", srv.URL)
+
+	_, err = Check(&CheckParams{
+		Product:            "test-app",
+		Version:            "1.0.0",
+		SignaturePublicKey: pub,
+		RetryPolicy:        &RetryPolicy{MaxAttempts: 1, Timeout: time.Second},
+	})
+	if err == nil {
+		t.Fatal("expected an error for an invalid signature")
+	}
+}